@@ -0,0 +1,157 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/cache/imagestore"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// ImageDetails holds the content-addressable parts of a built image that
+// the cache needs in order to recognize it again: the digest it was
+// pushed/tagged with, and the local image ID the daemon assigned it.
+type ImageDetails struct {
+	Digest string `yaml:"digest,omitempty"`
+	ID     string `yaml:"id,omitempty"`
+
+	// Ref optionally pins this cache entry to a fully-qualified
+	// `repository@sha256:...` reference, so that the cache can be matched
+	// against a specific remote registry digest rather than just the local
+	// workspace-hash tag or image ID.
+	Ref string `yaml:"ref,omitempty"`
+}
+
+// ArtifactCache maps an artifact's workspace hash to the details of the
+// image that was last built for it.
+type ArtifactCache map[string]ImageDetails
+
+// Cache holds any state needed to look up and reuse previously built
+// artifacts instead of rebuilding them.
+type Cache struct {
+	artifactCache ArtifactCache
+	client        docker.LocalDaemon
+	cacheFile     string
+	useCache      bool
+	pushImages    bool
+	localCluster  bool
+	imageList     []types.ImageSummary
+
+	// store is the content-addressable backend for this cache. It is
+	// optional: a nil store means the cache only knows how to look artifacts
+	// up through the legacy artifactCache/imageList path.
+	store imagestore.Store
+
+	// hasher computes the workspace hash artifacts are cached under.
+	hasher Hasher
+}
+
+// NewCache returns a Cache backed by the cache file at opts.CacheFile. If
+// caching is disabled, the returned Cache is a no-op: RetrieveCachedArtifacts
+// returns every artifact unchanged.
+func NewCache(opts *config.SkaffoldOptions, needsPush, localCluster bool) (*Cache, error) {
+	if !opts.CacheArtifacts {
+		return &Cache{}, nil
+	}
+
+	cf, err := resolveCacheFile(opts.CacheFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving cache file")
+	}
+
+	artifactCache, err := retrieveArtifactCache(cf)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving artifact cache")
+	}
+
+	client, err := docker.NewAPIClient(opts.KubeContext)
+	if err != nil {
+		// No local daemon is available; the cache still works for
+		// artifacts that exist remotely.
+		client = nil
+	}
+
+	storeDir, err := imagestore.DefaultDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving image store directory")
+	}
+	store, err := imagestore.NewFilesystemStore(storeDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening image store")
+	}
+	if err := MigrateArtifactCache(artifactCache, store); err != nil {
+		return nil, errors.Wrap(err, "migrating legacy artifact cache")
+	}
+
+	return &Cache{
+		artifactCache: artifactCache,
+		client:        client,
+		cacheFile:     cf,
+		useCache:      true,
+		pushImages:    needsPush,
+		localCluster:  localCluster,
+		store:         store,
+		hasher:        hasherFromOptions(opts),
+	}, nil
+}
+
+// resolveCacheFile returns cacheFile, or the default ~/.skaffold/cache when
+// it's empty. It does not create the file; a missing one reads as empty.
+func resolveCacheFile(cacheFile string) (string, error) {
+	if cacheFile != "" {
+		return cacheFile, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "retrieving home directory")
+	}
+	return filepath.Join(home, ".skaffold", "cache"), nil
+}
+
+func retrieveArtifactCache(cacheFile string) (ArtifactCache, error) {
+	cache := ArtifactCache{}
+	contents, err := ioutil.ReadFile(cacheFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(contents, &cache); err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+// save writes the in-memory artifact cache back to the cache file.
+func (c *Cache) save() error {
+	if !c.useCache {
+		return nil
+	}
+	data, err := yaml.Marshal(c.artifactCache)
+	if err != nil {
+		return errors.Wrap(err, "marshalling artifact cache")
+	}
+	return ioutil.WriteFile(c.cacheFile, data, 0644)
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "fmt"
+
+// noCache is a Cache with nothing in its artifact cache, used by tests that
+// only care about the "not cached yet" path.
+var noCache = &Cache{useCache: true}
+
+var (
+	digest   = "sha256:6a129cc0f5c479d6a9b21b21b0c2c0b1ef1c81a8d0b5c62e6c7bb6f0f2d5f1a2"
+	image    = fmt.Sprintf("image@%s", digest)
+	imageOne = fmt.Sprintf("image1@%s", "sha256:b6f6d3d4d6e6e0a5b3c0d5f4a9e3d2c1b0a9f8e7d6c5b4a3928170695c4b3a2")
+)
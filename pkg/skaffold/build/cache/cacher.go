@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// Cacher retrieves previously built artifacts so a build can skip
+// rebuilding them, and records freshly built ones so a later build can do
+// the same. Cache does this against the local daemon and an
+// on-disk/in-memory imagestore.Store; RemoteCache does the equivalent
+// against a shared registry, so CI runners and other developers can reuse
+// each other's builds without a local Docker daemon.
+type Cacher interface {
+	RetrieveCachedArtifacts(ctx context.Context, out io.Writer, artifacts []*latest.Artifact) ([]*latest.Artifact, []build.Artifact, error)
+	CacheArtifacts(ctx context.Context, artifacts []*latest.Artifact, built []build.Artifact) error
+}
+
+var _ Cacher = (*Cache)(nil)
+
+// NewCacher returns the Cacher selected by opts: a RemoteCache backed by
+// opts.CacheRepo when one is configured, so a shared registry can be used
+// without a local Docker daemon, otherwise the local Cache returned by
+// NewCache.
+func NewCacher(opts *config.SkaffoldOptions, needsPush, localCluster bool) (Cacher, error) {
+	if opts.CacheArtifacts && opts.CacheRepo != "" {
+		return NewRemoteCache(opts.CacheRepo), nil
+	}
+	return NewCache(opts, needsPush, localCluster)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+)
+
+// layerDigestHashAlgorithm is the opts.CacheHashAlgorithm value that
+// selects LayerDigestHasher over the default workspaceHasher.
+const layerDigestHashAlgorithm = "layer-digest"
+
+// hasherFromOptions returns the Hasher selected by opts.CacheHashAlgorithm,
+// defaulting to workspaceHasher - the only strategy available before
+// LayerDigestHasher existed - when it's unset.
+func hasherFromOptions(opts *config.SkaffoldOptions) Hasher {
+	if opts.CacheHashAlgorithm == layerDigestHashAlgorithm {
+		return LayerDigestHasher{}
+	}
+	return workspaceHasher{}
+}
+
+// Hasher computes a value that changes whenever an artifact's build inputs
+// change, so the cache can tell whether a previous build is still valid.
+// It's an interface, rather than the package-level function variable this
+// used to be, so that alternative strategies (hashing file contents,
+// hashing layer digests, ...) are just different implementations instead of
+// mutually-exclusive monkey-patches of the same function.
+type Hasher interface {
+	Hash(ctx context.Context, artifact *latest.Artifact) (string, error)
+}
+
+// workspaceHasher is the default Hasher: it hashes the contents of every
+// file the artifact depends on, in sorted order, so the result is stable
+// regardless of how the dependencies were discovered. A reformat of a
+// dependency still invalidates the cache, since the bytes on disk change;
+// an mtime bump alone does not, since only file contents are hashed.
+type workspaceHasher struct{}
+
+func (workspaceHasher) Hash(ctx context.Context, artifact *latest.Artifact) (string, error) {
+	deps, err := docker.DependenciesForArtifact(ctx, artifact)
+	if err != nil {
+		return "", errors.Wrapf(err, "getting dependencies for %s", artifact.ImageName)
+	}
+	sort.Strings(deps)
+
+	h := sha256.New()
+	for _, dep := range deps {
+		if err := hashFileContents(h, dep); err != nil {
+			return "", errors.Wrapf(err, "hashing contents of %s", dep)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashFileContents(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+)
+
+// mockHasher is a Hasher that looks an artifact's hash up by image name
+// instead of actually hashing its workspace, so tests can fix the hashes
+// artifacts are assigned without touching the filesystem.
+type mockHasher map[string]string
+
+func (m mockHasher) Hash(_ context.Context, artifact *latest.Artifact) (string, error) {
+	return m[artifact.ImageName], nil
+}
+
+func TestHasherFromOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		expected  Hasher
+	}{
+		{name: "unset, defaults to workspaceHasher", expected: workspaceHasher{}},
+		{name: "unrecognized value, defaults to workspaceHasher", algorithm: "bogus", expected: workspaceHasher{}},
+		{name: "layer-digest selects LayerDigestHasher", algorithm: layerDigestHashAlgorithm, expected: LayerDigestHasher{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := &config.SkaffoldOptions{CacheHashAlgorithm: test.algorithm}
+			if actual := hasherFromOptions(opts); actual != test.expected {
+				t.Errorf("hasherFromOptions() = %#v, want %#v", actual, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// index is the on-disk representation of a filesystemStore.
+type index struct {
+	Images map[string]Image  `yaml:"images"`
+	Refs   map[string]string `yaml:"refs"`
+}
+
+// filesystemStore persists its index under a directory on disk, so that the
+// cache survives across skaffold invocations without requiring a shared
+// backend like BoltDB or a remote gRPC store.
+type filesystemStore struct {
+	mu        sync.Mutex
+	indexFile string
+	index     index
+}
+
+// DefaultDir is where NewFilesystemStore persists its index when no
+// directory is given explicitly. It's a sibling of, not a child of,
+// ~/.skaffold/cache: that path is the legacy YAML cache *file*, so
+// MkdirAll-ing anything underneath it fails with ENOTDIR for every user who
+// already has one.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "retrieving home directory")
+	}
+	return filepath.Join(home, ".skaffold", "cache-store"), nil
+}
+
+// NewFilesystemStore returns a Store backed by an index file under dir,
+// loading any existing index found there.
+func NewFilesystemStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "creating cache directory")
+	}
+
+	s := &filesystemStore{
+		indexFile: filepath.Join(dir, "index.yaml"),
+		index: index{
+			Images: map[string]Image{},
+			Refs:   map[string]string{},
+		},
+	}
+
+	contents, err := ioutil.ReadFile(s.indexFile)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "reading cache index")
+	}
+
+	if err := yaml.Unmarshal(contents, &s.index); err != nil {
+		return nil, errors.Wrap(err, "parsing cache index")
+	}
+	return s, nil
+}
+
+func (s *filesystemStore) Get(id string) (Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	img, ok := s.index.Images[id]
+	return img, ok
+}
+
+func (s *filesystemStore) Search(prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []string
+	for id := range s.index.Images {
+		if len(id) >= len(prefix) && id[:len(prefix)] == prefix {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *filesystemStore) Resolve(ref string) (Image, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.index.Refs[ref]
+	if !ok {
+		return Image{}, false
+	}
+	return s.index.Images[id], true
+}
+
+func (s *filesystemStore) AddDigest(ref, id, digest string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index.Images[id]; !ok {
+		s.index.Images[id] = Image{ID: id, Digest: digest}
+	}
+	s.index.Refs[ref] = id
+	return s.saveLocked()
+}
+
+func (s *filesystemStore) References(id string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var refs []string
+	for ref, refID := range s.index.Refs {
+		if refID == id {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func (s *filesystemStore) saveLocked() error {
+	data, err := yaml.Marshal(s.index)
+	if err != nil {
+		return errors.Wrap(err, "marshalling cache index")
+	}
+	return ioutil.WriteFile(s.indexFile, data, 0644)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagestore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNewFilesystemStoreAlongsideLegacyCacheFile makes sure the store can be
+// opened when its parent directory (e.g. ~/.skaffold) already contains
+// skaffold's legacy YAML cache *file* (e.g. ~/.skaffold/cache) - the layout
+// every pre-existing skaffold user has. A store directory nested under that
+// file's own path, rather than alongside it, would make every MkdirAll fail
+// with ENOTDIR.
+func TestNewFilesystemStoreAlongsideLegacyCacheFile(t *testing.T) {
+	home, err := ioutil.TempDir("", "skaffold-home")
+	if err != nil {
+		t.Fatalf("creating temp dir: %v", err)
+	}
+
+	skaffoldDir := filepath.Join(home, ".skaffold")
+	if err := os.MkdirAll(skaffoldDir, 0755); err != nil {
+		t.Fatalf("creating .skaffold dir: %v", err)
+	}
+	legacyCacheFile := filepath.Join(skaffoldDir, "cache")
+	if err := ioutil.WriteFile(legacyCacheFile, []byte("image1: {digest: sha256:abc}\n"), 0644); err != nil {
+		t.Fatalf("writing legacy cache file: %v", err)
+	}
+
+	storeDir := filepath.Join(skaffoldDir, "cache-store")
+	if _, err := NewFilesystemStore(storeDir); err != nil {
+		t.Fatalf("NewFilesystemStore should succeed alongside a legacy cache file, got: %v", err)
+	}
+}
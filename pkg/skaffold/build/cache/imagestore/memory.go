@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagestore
+
+import "strings"
+
+// memoryStore is an in-memory Store, useful for tests and for skaffold runs
+// that don't want the cache to persist across invocations.
+type memoryStore struct {
+	images map[string]Image
+	refs   map[string]string // ref -> image ID
+}
+
+// NewMemoryStore returns a Store that keeps everything in memory.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		images: map[string]Image{},
+		refs:   map[string]string{},
+	}
+}
+
+func (s *memoryStore) Get(id string) (Image, bool) {
+	img, ok := s.images[id]
+	return img, ok
+}
+
+func (s *memoryStore) Search(prefix string) []string {
+	var ids []string
+	for id := range s.images {
+		if strings.HasPrefix(id, prefix) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (s *memoryStore) Resolve(ref string) (Image, bool) {
+	id, ok := s.refs[ref]
+	if !ok {
+		return Image{}, false
+	}
+	return s.images[id], true
+}
+
+func (s *memoryStore) AddDigest(ref, id, digest string) error {
+	if _, ok := s.images[id]; !ok {
+		s.images[id] = Image{ID: id, Digest: digest}
+	}
+	s.refs[ref] = id
+	return nil
+}
+
+func (s *memoryStore) References(id string) []string {
+	var refs []string
+	for ref, refID := range s.refs {
+		if refID == id {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package imagestore
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestMemoryStoreAddDigestAndGet(t *testing.T) {
+	s := NewMemoryStore()
+
+	if err := s.AddDigest("workspace-hash", "sha256:abcdef", "sha256:content"); err != nil {
+		t.Fatalf("AddDigest: %v", err)
+	}
+	if err := s.AddDigest("myrepo@sha256:123", "sha256:abcdef", "sha256:content"); err != nil {
+		t.Fatalf("AddDigest: %v", err)
+	}
+
+	img, ok := s.Get("sha256:abcdef")
+	testutil.CheckDeepEqual(t, true, ok)
+	testutil.CheckDeepEqual(t, "sha256:abcdef", img.ID)
+	testutil.CheckDeepEqual(t, "sha256:content", img.Digest)
+
+	refs := s.References("sha256:abcdef")
+	sort.Strings(refs)
+	testutil.CheckDeepEqual(t, []string{"myrepo@sha256:123", "workspace-hash"}, refs)
+}
+
+func TestMemoryStoreResolve(t *testing.T) {
+	s := NewMemoryStore()
+	s.AddDigest("myrepo@sha256:123", "sha256:abcdef", "myrepo@sha256:123")
+
+	img, ok := s.Resolve("myrepo@sha256:123")
+	testutil.CheckDeepEqual(t, true, ok)
+	testutil.CheckDeepEqual(t, "sha256:abcdef", img.ID)
+	testutil.CheckDeepEqual(t, "myrepo@sha256:123", img.Digest)
+
+	_, ok = s.Resolve("myrepo@sha256:456")
+	testutil.CheckDeepEqual(t, false, ok)
+}
+
+func TestMemoryStoreSearch(t *testing.T) {
+	s := NewMemoryStore()
+	s.AddDigest("ref1", "sha256:abc123", "")
+	s.AddDigest("ref2", "sha256:abc456", "")
+	s.AddDigest("ref3", "sha256:def789", "")
+
+	ids := s.Search("sha256:abc")
+	sort.Strings(ids)
+	testutil.CheckDeepEqual(t, []string{"sha256:abc123", "sha256:abc456"}, ids)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package imagestore provides a content-addressable store for built images,
+// decoupled from how those images are named. It mirrors the split moby uses
+// internally between its image store (content, keyed by ID) and its
+// reference store (names, mapping onto an ID): that split is what lets a
+// single built image carry several tags, be looked up by a short ID prefix,
+// and be backed by different storage engines (in-memory, on-disk, remote)
+// without the build cache caring which one is in use.
+package imagestore
+
+// Image is the content-addressable record the store keeps for a single
+// built image.
+type Image struct {
+	ID     string
+	Digest string
+}
+
+// Store is a content-addressable store of built images, keyed by image ID.
+type Store interface {
+	// Get returns the image recorded under id.
+	Get(id string) (Image, bool)
+
+	// Search returns the IDs of every recorded image whose ID has prefix,
+	// so that a short ID (e.g. `--cache-lookup=abc123`) can resolve to the
+	// image it names.
+	Search(prefix string) []string
+
+	// Resolve returns the image that ref was last recorded against with
+	// AddDigest. Unlike Search, it matches ref exactly rather than as an
+	// image ID prefix, since a ref (a workspace hash or a
+	// `repository@digest` reference) is never itself an image ID.
+	Resolve(ref string) (Image, bool)
+
+	// AddDigest records that ref names the image with the given ID. ref is
+	// typically a workspace hash or a `repository@digest` reference; id is
+	// the ID the builder/daemon assigned the image. digest is the image's
+	// content digest, recorded the first time id is seen; it may be empty
+	// if the caller doesn't know it yet.
+	AddDigest(ref, id, digest string) error
+
+	// References returns every ref that has been recorded against id.
+	References(id string) []string
+}
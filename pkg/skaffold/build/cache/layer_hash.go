@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+)
+
+// These are package-level variables, like remoteDigest and
+// imgExistsRemotely in retrieve.go, so tests can stub them out instead of
+// needing a real Dockerfile and base image to resolve against.
+var (
+	readDockerfileInstructions = docker.ReadDockerfileInstructions
+	baseImageLayerDigests      = docker.BaseImageLayerDigests
+	copySourcesForArtifact     = docker.CopySourcesForArtifact
+	digestOfFile               = docker.Digest
+)
+
+// LayerDigestHasher derives a hash from the resolved base image's layer
+// digests, the Dockerfile instructions, and the content digests of the
+// Dockerfile's COPY/ADD sources, similar to how BuildKit derives its own
+// cache keys. None of these inputs depend on local file metadata, so the
+// resulting hash is stable across an mtime change and portable between
+// machines building the same artifact. COPY/ADD sources are hashed by
+// content, so reformatting one of them still changes the hash.
+type LayerDigestHasher struct{}
+
+func (LayerDigestHasher) Hash(ctx context.Context, artifact *latest.Artifact) (string, error) {
+	instructions, err := readDockerfileInstructions(artifact.Workspace, artifact.DockerArtifact)
+	if err != nil {
+		return "", errors.Wrapf(err, "reading dockerfile for %s", artifact.ImageName)
+	}
+
+	baseLayerDigests, err := baseImageLayerDigests(ctx, artifact)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving base image layers for %s", artifact.ImageName)
+	}
+
+	copySources, err := copySourcesForArtifact(artifact)
+	if err != nil {
+		return "", errors.Wrapf(err, "resolving COPY/ADD sources for %s", artifact.ImageName)
+	}
+
+	h := sha256.New()
+	for _, digest := range baseLayerDigests {
+		io.WriteString(h, digest)
+	}
+	for _, instruction := range instructions {
+		io.WriteString(h, instruction)
+	}
+	for _, src := range copySources {
+		digest, err := digestOfFile(src)
+		if err != nil {
+			return "", errors.Wrapf(err, "digesting %s", src)
+		}
+		io.WriteString(h, digest)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
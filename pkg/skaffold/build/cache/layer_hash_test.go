@@ -0,0 +1,68 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestLayerDigestHasherHash(t *testing.T) {
+	defer func(f func(string, *latest.DockerArtifact) ([]string, error)) { readDockerfileInstructions = f }(readDockerfileInstructions)
+	defer func(f func(context.Context, *latest.Artifact) ([]string, error)) { baseImageLayerDigests = f }(baseImageLayerDigests)
+	defer func(f func(*latest.Artifact) ([]string, error)) { copySourcesForArtifact = f }(copySourcesForArtifact)
+	defer func(f func(string) (string, error)) { digestOfFile = f }(digestOfFile)
+
+	readDockerfileInstructions = func(string, *latest.DockerArtifact) ([]string, error) {
+		return []string{"FROM base", "COPY . ."}, nil
+	}
+	baseImageLayerDigests = func(context.Context, *latest.Artifact) ([]string, error) {
+		return []string{"layer1digest", "layer2digest"}, nil
+	}
+	copySourcesForArtifact = func(*latest.Artifact) ([]string, error) {
+		return []string{"main.go"}, nil
+	}
+	digestOfFile = func(src string) (string, error) {
+		if src != "main.go" {
+			t.Fatalf("unexpected source %q", src)
+		}
+		return "filedigest123", nil
+	}
+
+	hash, err := (LayerDigestHasher{}).Hash(context.Background(), &latest.Artifact{ImageName: "image"})
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+
+	const expected = "2878feb42c50985ef969d2f4abacaeea97dba7d152e910b63452b77f3f85929c"
+	testutil.CheckDeepEqual(t, expected, hash)
+}
+
+func TestLayerDigestHasherHashPropagatesErrors(t *testing.T) {
+	defer func(f func(string, *latest.DockerArtifact) ([]string, error)) { readDockerfileInstructions = f }(readDockerfileInstructions)
+
+	readDockerfileInstructions = func(string, *latest.DockerArtifact) ([]string, error) {
+		return nil, errors.New("bad dockerfile")
+	}
+
+	_, err := (LayerDigestHasher{}).Hash(context.Background(), &latest.Artifact{ImageName: "image"})
+	testutil.CheckError(t, true, err)
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import "github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/cache/imagestore"
+
+// MigrateArtifactCache copies every entry of the legacy YAML artifact cache
+// into store, so that an existing ~/.skaffold/cache file keeps working once
+// the cache is backed by an imagestore.Store. It is meant to be run once,
+// the first time a store-backed cache is used; re-running it is harmless
+// since AddDigest is idempotent.
+func MigrateArtifactCache(ac ArtifactCache, store imagestore.Store) error {
+	for workspaceHash, details := range ac {
+		id := details.ID
+		if id == "" {
+			// Entries with no local image ID were built before this
+			// skaffold version tracked it; key the store off the digest
+			// instead so it still has something to match against.
+			id = details.Digest
+		}
+		if id == "" {
+			continue
+		}
+
+		if err := store.AddDigest(workspaceHash, id, details.Digest); err != nil {
+			return err
+		}
+		if details.Digest != "" {
+			if err := store.AddDigest(details.Digest, id, details.Digest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
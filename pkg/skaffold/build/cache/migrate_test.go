@@ -0,0 +1,44 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/cache/imagestore"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestMigrateArtifactCache(t *testing.T) {
+	ac := ArtifactCache{
+		"workspace-hash-1": ImageDetails{ID: "id1", Digest: "sha256:digest1"},
+		"workspace-hash-2": ImageDetails{Digest: "sha256:digest2"},
+		"workspace-hash-3": ImageDetails{},
+	}
+	store := imagestore.NewMemoryStore()
+
+	if err := MigrateArtifactCache(ac, store); err != nil {
+		t.Fatalf("MigrateArtifactCache: %v", err)
+	}
+
+	img, ok := store.Get("id1")
+	testutil.CheckDeepEqual(t, true, ok)
+	testutil.CheckDeepEqual(t, "id1", img.ID)
+
+	ids := store.Search("sha256:digest2")
+	testutil.CheckDeepEqual(t, 1, len(ids))
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+)
+
+// CacheArtifacts records the outcome of a fresh build into the cache, so a
+// later RetrieveCachedArtifacts call can reuse it instead of rebuilding.
+// It's meant to be called once per build, right after every artifact in
+// built has finished building. Artifacts with no matching entry in built -
+// for instance ones RetrieveCachedArtifacts already served from the cache -
+// are left untouched.
+func (c *Cache) CacheArtifacts(ctx context.Context, artifacts []*latest.Artifact, built []build.Artifact) error {
+	if !c.useCache {
+		return nil
+	}
+
+	builtByName := make(map[string]build.Artifact, len(built))
+	for _, b := range built {
+		builtByName[b.ImageName] = b
+	}
+
+	for _, a := range artifacts {
+		b, ok := builtByName[a.ImageName]
+		if !ok {
+			continue
+		}
+
+		details := c.detailsForBuiltArtifact(ctx, b)
+		c.artifactCache[a.WorkspaceHash] = details
+
+		if c.store != nil && details.ID != "" {
+			if err := c.store.AddDigest(a.WorkspaceHash, details.ID, details.Digest); err != nil {
+				return errors.Wrapf(err, "recording built artifact %s in image store", a.ImageName)
+			}
+			if details.Digest != "" {
+				if err := c.store.AddDigest(details.Digest, details.ID, details.Digest); err != nil {
+					return errors.Wrapf(err, "recording digest for %s in image store", a.ImageName)
+				}
+			}
+		}
+	}
+
+	return c.save()
+}
+
+// detailsForBuiltArtifact gathers everything the cache knows how to learn
+// about a freshly built image: its local daemon ID, the digest it resolves
+// to, and - when that digest is exposed through a canonical
+// `repository@sha256:...` tag - a Ref pinning the entry to it.
+func (c *Cache) detailsForBuiltArtifact(ctx context.Context, b build.Artifact) ImageDetails {
+	var details ImageDetails
+
+	if c.client != nil {
+		if id, err := c.client.ImageID(ctx, b.Tag); err == nil {
+			details.ID = id
+		}
+	}
+	if d, err := remoteDigest(b.Tag); err == nil {
+		details.Digest = d
+	}
+	if ref, err := ParseArtifactRef(b.Tag); err == nil && IsCanonical(ref) {
+		details.Ref = ref.String()
+	}
+
+	return details
+}
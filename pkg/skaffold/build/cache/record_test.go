@@ -0,0 +1,130 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/cache/imagestore"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestCacheArtifacts(t *testing.T) {
+	tests := []struct {
+		name      string
+		useCache  bool
+		artifacts []*latest.Artifact
+		built     []build.Artifact
+		digest    string
+		expected  ArtifactCache
+	}{
+		{
+			name:      "records id and digest for a freshly built artifact",
+			useCache:  true,
+			artifacts: []*latest.Artifact{{ImageName: "image", WorkspaceHash: "hash"}},
+			built:     []build.Artifact{{ImageName: "image", Tag: "image:hash"}},
+			digest:    "sha256:abc",
+			expected:  ArtifactCache{"hash": ImageDetails{ID: "id", Digest: "sha256:abc"}},
+		},
+		{
+			name:      "artifact served from cache isn't touched",
+			useCache:  true,
+			artifacts: []*latest.Artifact{{ImageName: "image", WorkspaceHash: "hash"}},
+			built:     nil,
+			expected:  ArtifactCache{},
+		},
+		{
+			name:      "useCache is false, nothing recorded",
+			artifacts: []*latest.Artifact{{ImageName: "image", WorkspaceHash: "hash"}},
+			built:     []build.Artifact{{ImageName: "image", Tag: "image:hash"}},
+			expected:  ArtifactCache{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			originalRemoteDigest := remoteDigest
+			remoteDigest = func(string) (string, error) { return test.digest, nil }
+			defer func() { remoteDigest = originalRemoteDigest }()
+
+			c := &Cache{useCache: test.useCache, artifactCache: ArtifactCache{}}
+			c.client = docker.NewLocalDaemon(&testutil.FakeAPIClient{
+				TagToImageID: map[string]string{"image:hash": "id"},
+			}, nil, false)
+
+			if test.useCache {
+				cacheFile, err := ioutil.TempFile("", "skaffold-artifact-cache")
+				if err != nil {
+					t.Fatalf("creating temp cache file: %v", err)
+				}
+				defer os.Remove(cacheFile.Name())
+				c.cacheFile = cacheFile.Name()
+			}
+
+			err := c.CacheArtifacts(context.Background(), test.artifacts, test.built)
+			if err != nil {
+				t.Fatalf("CacheArtifacts: %v", err)
+			}
+			testutil.CheckDeepEqual(t, test.expected, c.artifactCache)
+		})
+	}
+}
+
+func TestCacheArtifactsRecordsInStore(t *testing.T) {
+	originalRemoteDigest := remoteDigest
+	remoteDigest = func(string) (string, error) { return "sha256:abc", nil }
+	defer func() { remoteDigest = originalRemoteDigest }()
+
+	store := imagestore.NewMemoryStore()
+	cacheFile, err := ioutil.TempFile("", "skaffold-artifact-cache")
+	if err != nil {
+		t.Fatalf("creating temp cache file: %v", err)
+	}
+	defer os.Remove(cacheFile.Name())
+
+	c := &Cache{
+		useCache:      true,
+		artifactCache: ArtifactCache{},
+		cacheFile:     cacheFile.Name(),
+		store:         store,
+		client: docker.NewLocalDaemon(&testutil.FakeAPIClient{
+			TagToImageID: map[string]string{"image:hash": "id"},
+		}, nil, false),
+	}
+
+	artifacts := []*latest.Artifact{{ImageName: "image", WorkspaceHash: "hash"}}
+	built := []build.Artifact{{ImageName: "image", Tag: "image:hash"}}
+	if err := c.CacheArtifacts(context.Background(), artifacts, built); err != nil {
+		t.Fatalf("CacheArtifacts: %v", err)
+	}
+
+	img, ok := store.Resolve("hash")
+	testutil.CheckDeepEqual(t, true, ok)
+	testutil.CheckDeepEqual(t, "id", img.ID)
+	testutil.CheckDeepEqual(t, "sha256:abc", img.Digest)
+
+	img, ok = store.Resolve("sha256:abc")
+	testutil.CheckDeepEqual(t, true, ok)
+	testutil.CheckDeepEqual(t, "id", img.ID)
+	testutil.CheckDeepEqual(t, "sha256:abc", img.Digest)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution/reference"
+)
+
+// ArtifactRef identifies a built artifact, either by a mutable tag
+// (NamedTagged) or by an immutable content digest (Canonical). Pinning a
+// cache entry to a Canonical reference lets a lookup survive a tag being
+// retargeted in a remote registry, since the digest it was built at never
+// changes.
+type ArtifactRef interface {
+	fmt.Stringer
+
+	// Name returns the repository portion of the reference, without a tag
+	// or digest.
+	Name() string
+}
+
+// namedTagged identifies an artifact by a mutable `repository:tag` reference.
+type namedTagged struct {
+	reference.NamedTagged
+}
+
+// canonical identifies an artifact by its immutable `repository@digest` reference.
+type canonical struct {
+	reference.Canonical
+}
+
+// ParseArtifactRef parses s as either a `repository:tag` or a
+// `repository@sha256:...` reference, modeled on Docker's reference.Parse.
+// Canonical (digest) references are preferred: if s carries both a tag and a
+// digest, the digest wins, matching how the daemon resolves such references.
+func ParseArtifactRef(s string) (ArtifactRef, error) {
+	ref, err := reference.ParseNormalizedNamed(s)
+	if err != nil {
+		return nil, fmt.Errorf("parsing artifact reference %q: %w", s, err)
+	}
+
+	if canon, ok := ref.(reference.Canonical); ok {
+		return &canonical{Canonical: canon}, nil
+	}
+
+	tagged, ok := ref.(reference.NamedTagged)
+	if !ok {
+		return nil, fmt.Errorf("artifact reference %q has neither a tag nor a digest", s)
+	}
+	return &namedTagged{NamedTagged: tagged}, nil
+}
+
+// IsCanonical reports whether ref identifies an artifact by content digest
+// rather than by a mutable tag.
+func IsCanonical(ref ArtifactRef) bool {
+	_, ok := ref.(*canonical)
+	return ok
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestParseArtifactRef(t *testing.T) {
+	tests := []struct {
+		name        string
+		ref         string
+		shouldErr   bool
+		isCanonical bool
+	}{
+		{
+			name: "tagged reference",
+			ref:  "gcr.io/project/image:v1",
+		},
+		{
+			name:        "canonical reference",
+			ref:         "gcr.io/project/image@sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4",
+			isCanonical: true,
+		},
+		{
+			name:      "missing tag and digest",
+			ref:       "gcr.io/project/image",
+			shouldErr: true,
+		},
+		{
+			name:      "invalid reference",
+			ref:       "not a valid ref!!",
+			shouldErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ref, err := ParseArtifactRef(test.ref)
+			testutil.CheckError(t, test.shouldErr, err)
+			if test.shouldErr {
+				return
+			}
+			testutil.CheckDeepEqual(t, test.isCanonical, IsCanonical(ref))
+		})
+	}
+}
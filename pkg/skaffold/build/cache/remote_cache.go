@@ -0,0 +1,191 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/pkg/errors"
+)
+
+// remoteCacheEntryExpiry is how long a remote cache entry is considered
+// valid. It's written into the entry's `expires` annotation so that
+// registries with a tag-expiry lifecycle policy can garbage collect stale
+// entries without skaffold having to do so itself.
+const remoteCacheEntryExpiry = 30 * 24 * time.Hour
+
+// These are package-level variables, like remoteDigest and
+// imgExistsRemotely in retrieve.go, so tests can stub out the registry
+// round-trip instead of needing a real remote repository.
+var (
+	pushManifestWithAnnotations = docker.PushManifestWithAnnotations
+	remoteManifestAnnotations   = docker.RemoteManifestAnnotations
+)
+
+// RemoteCache is a Cacher backed by a registry instead of the local daemon.
+// It stores each build result's ImageDetails as the annotations of a tiny
+// OCI artifact tagged `<repo>:cache-<workspacehash>`, so that CI runners and
+// multiple developers can share build results without a local Docker
+// daemon.
+type RemoteCache struct {
+	repo   string
+	hasher Hasher
+}
+
+var _ Cacher = (*RemoteCache)(nil)
+
+// NewRemoteCache returns a RemoteCache that stores its entries in repo
+// (e.g. "gcr.io/my-project/build-cache").
+func NewRemoteCache(repo string) *RemoteCache {
+	return &RemoteCache{repo: repo, hasher: workspaceHasher{}}
+}
+
+func (r *RemoteCache) cacheTag(workspaceHash string) string {
+	return fmt.Sprintf("%s:cache-%s", r.repo, workspaceHash)
+}
+
+// RetrieveCachedArtifacts checks, for each artifact, whether a cache entry
+// exists remotely for its current workspace hash and whether the image it
+// points at is still present in its registry, skipping the build entirely
+// when both hold.
+func (r *RemoteCache) RetrieveCachedArtifacts(ctx context.Context, out io.Writer, artifacts []*latest.Artifact) ([]*latest.Artifact, []build.Artifact, error) {
+	var needToBuild []*latest.Artifact
+	var built []build.Artifact
+
+	for _, a := range artifacts {
+		hash, err := r.hasher.Hash(ctx, a)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "getting hash for artifact %s", a.ImageName)
+		}
+		a.WorkspaceHash = hash
+
+		entry, err := r.retrieveCacheEntry(hash)
+		if err != nil {
+			needToBuild = append(needToBuild, a)
+			continue
+		}
+
+		if entry.expired() || !imgExistsRemotely(entry.Tag, entry.Digest) {
+			needToBuild = append(needToBuild, a)
+			continue
+		}
+
+		color.Green.Fprintf(out, "Found remote cached artifact for %s\n", a.ImageName)
+		built = append(built, build.Artifact{
+			ImageName: a.ImageName,
+			Tag:       entry.Tag,
+		})
+	}
+
+	return needToBuild, built, nil
+}
+
+// CacheArtifacts uploads a remote cache entry for each freshly built
+// artifact, so a later RetrieveCachedArtifacts call - from this machine or
+// another - can reuse it instead of rebuilding. Artifacts with no matching
+// entry in built are left untouched.
+func (r *RemoteCache) CacheArtifacts(ctx context.Context, artifacts []*latest.Artifact, built []build.Artifact) error {
+	builtByName := make(map[string]build.Artifact, len(built))
+	for _, b := range built {
+		builtByName[b.ImageName] = b
+	}
+
+	for _, a := range artifacts {
+		b, ok := builtByName[a.ImageName]
+		if !ok {
+			continue
+		}
+
+		digest, err := remoteDigest(b.Tag)
+		if err != nil {
+			return errors.Wrapf(err, "getting digest for artifact %s", a.ImageName)
+		}
+
+		if err := r.UploadCacheEntry(ctx, a.WorkspaceHash, ImageDetails{Digest: digest}, b.Tag); err != nil {
+			return errors.Wrapf(err, "uploading cache entry for artifact %s", a.ImageName)
+		}
+	}
+	return nil
+}
+
+// UploadCacheEntry pushes an OCI artifact manifest recording built, so a
+// later RetrieveCachedArtifacts call from this machine or another can reuse
+// it instead of rebuilding. It's meant to be called right after a
+// successful build.
+func (r *RemoteCache) UploadCacheEntry(ctx context.Context, hash string, built ImageDetails, tag string) error {
+	entry := remoteCacheEntry{
+		Digest:  built.Digest,
+		ID:      built.ID,
+		Tag:     tag,
+		Expires: time.Now().Add(remoteCacheEntryExpiry),
+	}
+	return pushManifestWithAnnotations(r.cacheTag(hash), entry.annotations())
+}
+
+// remoteCacheEntry is what's stored, as the manifest annotations of the
+// `cache-<workspacehash>` OCI artifact, for a single built artifact.
+type remoteCacheEntry struct {
+	Digest  string
+	ID      string
+	Tag     string
+	Expires time.Time
+}
+
+func (e remoteCacheEntry) annotations() map[string]string {
+	return map[string]string{
+		"digest":  e.Digest,
+		"id":      e.ID,
+		"tag":     e.Tag,
+		"expires": e.Expires.Format(time.RFC3339),
+	}
+}
+
+func (e remoteCacheEntry) expired() bool {
+	return !e.Expires.IsZero() && time.Now().After(e.Expires)
+}
+
+// retrieveCacheEntry HEADs the registry for the cache manifest of
+// workspaceHash and decodes its annotations into a remoteCacheEntry.
+func (r *RemoteCache) retrieveCacheEntry(workspaceHash string) (*remoteCacheEntry, error) {
+	cacheTag := r.cacheTag(workspaceHash)
+
+	annotations, err := remoteManifestAnnotations(cacheTag)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetching cache manifest for %s", cacheTag)
+	}
+
+	entry := &remoteCacheEntry{
+		Digest: annotations["digest"],
+		ID:     annotations["id"],
+		Tag:    annotations["tag"],
+	}
+	if expires := annotations["expires"]; expires != "" {
+		t, err := time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing expiry of cache manifest for %s", cacheTag)
+		}
+		entry.Expires = t
+	}
+	return entry, nil
+}
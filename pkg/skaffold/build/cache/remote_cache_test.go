@@ -0,0 +1,129 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/config"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/GoogleContainerTools/skaffold/testutil"
+)
+
+func TestNewCacherSelectsRemoteCache(t *testing.T) {
+	opts := &config.SkaffoldOptions{
+		CacheArtifacts: true,
+		CacheRepo:      "gcr.io/my-project/build-cache",
+	}
+
+	c, err := NewCacher(opts, false, false)
+	if err != nil {
+		t.Fatalf("NewCacher: %v", err)
+	}
+	if _, ok := c.(*RemoteCache); !ok {
+		t.Fatalf("NewCacher with CacheRepo set should return a *RemoteCache, got %T", c)
+	}
+}
+
+func TestRemoteCacheEntryExpired(t *testing.T) {
+	tests := []struct {
+		name    string
+		expires time.Time
+		want    bool
+	}{
+		{name: "no expiry set", expires: time.Time{}, want: false},
+		{name: "expires in the future", expires: time.Now().Add(time.Hour), want: false},
+		{name: "expired", expires: time.Now().Add(-time.Hour), want: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entry := remoteCacheEntry{Expires: test.expires}
+			if got := entry.expired(); got != test.want {
+				t.Errorf("expired() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// fakeRegistry is an in-memory stand-in for the registry
+// pushManifestWithAnnotations/remoteManifestAnnotations round-trip against,
+// keyed by cache tag.
+type fakeRegistry map[string]map[string]string
+
+func (f fakeRegistry) push(tag string, annotations map[string]string) error {
+	f[tag] = annotations
+	return nil
+}
+
+func (f fakeRegistry) annotations(tag string) (map[string]string, error) {
+	a, ok := f[tag]
+	if !ok {
+		return nil, fmt.Errorf("no cache manifest for %s", tag)
+	}
+	return a, nil
+}
+
+func TestRemoteCacheUploadThenRetrieve(t *testing.T) {
+	registry := fakeRegistry{}
+
+	originalPush := pushManifestWithAnnotations
+	pushManifestWithAnnotations = registry.push
+	defer func() { pushManifestWithAnnotations = originalPush }()
+
+	originalAnnotations := remoteManifestAnnotations
+	remoteManifestAnnotations = registry.annotations
+	defer func() { remoteManifestAnnotations = originalAnnotations }()
+
+	originalRemoteDigest := remoteDigest
+	remoteDigest = func(string) (string, error) { return "sha256:abc", nil }
+	defer func() { remoteDigest = originalRemoteDigest }()
+
+	originalImgExistsRemotely := imgExistsRemotely
+	imgExistsRemotely = func(_, _ string) bool { return true }
+	defer func() { imgExistsRemotely = originalImgExistsRemotely }()
+
+	r := NewRemoteCache("gcr.io/my-project/build-cache")
+	r.hasher = mockHasher{"image": "hash"}
+
+	artifacts := []*latest.Artifact{{ImageName: "image", WorkspaceHash: "hash"}}
+	built := []build.Artifact{{ImageName: "image", Tag: "image:hash"}}
+
+	if err := r.CacheArtifacts(context.Background(), artifacts, built); err != nil {
+		t.Fatalf("CacheArtifacts: %v", err)
+	}
+
+	needToBuild, retrieved, err := r.RetrieveCachedArtifacts(context.Background(), os.Stdout, artifacts)
+	if err != nil {
+		t.Fatalf("RetrieveCachedArtifacts: %v", err)
+	}
+	testutil.CheckDeepEqual(t, 0, len(needToBuild))
+	testutil.CheckDeepEqual(t, []build.Artifact{{ImageName: "image", Tag: "image:hash"}}, retrieved)
+}
+
+func TestRemoteCacheTag(t *testing.T) {
+	r := NewRemoteCache("gcr.io/my-project/build-cache")
+	got := r.cacheTag("workspace-hash")
+	want := "gcr.io/my-project/build-cache:cache-workspace-hash"
+	if got != want {
+		t.Errorf("cacheTag() = %q, want %q", got, want)
+	}
+}
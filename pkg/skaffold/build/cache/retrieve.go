@@ -0,0 +1,338 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/color"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
+	"github.com/docker/distribution/reference"
+	"github.com/docker/docker/api/types"
+	"github.com/pkg/errors"
+)
+
+// remoteDigest looks up the digest an image reference currently resolves to
+// in its registry. It is a package-level variable so tests can stub it out.
+var remoteDigest = docker.RemoteDigest
+
+// imgExistsRemotely reports whether tag still resolves to digest in its
+// registry. It is a package-level variable so tests can stub it out.
+var imgExistsRemotely = func(tag, digest string) bool {
+	d, err := remoteDigest(tag)
+	if err != nil {
+		return false
+	}
+	return d == digest
+}
+
+// cachedArtifactDetails describes what RetrieveCachedArtifacts needs to do
+// for a single artifact that was found in the artifact cache.
+type cachedArtifactDetails struct {
+	needsRebuild  bool
+	needsRetag    bool
+	needsPush     bool
+	prebuiltImage string
+	hashTag       string
+}
+
+// RetrieveCachedArtifacts splits artifacts into the ones that still need to
+// be built and the ones that can be reused from the cache, retagging or
+// pushing the reused images as necessary.
+func (c *Cache) RetrieveCachedArtifacts(ctx context.Context, out io.Writer, artifacts []*latest.Artifact) ([]*latest.Artifact, []build.Artifact, error) {
+	if !c.useCache {
+		return artifacts, nil, nil
+	}
+
+	if c.client != nil {
+		images, err := c.client.ImageList(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "listing local images")
+		}
+		c.imageList = images
+	}
+
+	var needToBuild []*latest.Artifact
+	var built []build.Artifact
+	for _, a := range artifacts {
+		details, err := c.retrieveCachedArtifactDetails(ctx, a)
+		if err != nil {
+			color.Red.Fprintf(out, "Unable to retrieve cached artifact for %s: %v\n", a.ImageName, err)
+			needToBuild = append(needToBuild, a)
+			continue
+		}
+		if details.needsRebuild {
+			needToBuild = append(needToBuild, a)
+			continue
+		}
+
+		if details.needsRetag {
+			fmt.Fprintf(out, "Retagging cached image of %s as %s\n", a.ImageName, details.hashTag)
+			if err := c.client.Tag(ctx, details.prebuiltImage, details.hashTag); err != nil {
+				return nil, nil, errors.Wrap(err, "retagging cached image")
+			}
+			details.prebuiltImage = details.hashTag
+		}
+
+		if details.needsPush {
+			fmt.Fprintf(out, "Pushing cached image for %s\n", a.ImageName)
+			if _, err := c.client.Push(ctx, out, details.hashTag); err != nil {
+				return nil, nil, errors.Wrap(err, "pushing cached image")
+			}
+		}
+
+		// Without a local daemon there's no prebuilt image to point at, only
+		// the hash tag found in the registry.
+		tag := details.prebuiltImage
+		if tag == "" {
+			tag = details.hashTag
+		}
+
+		color.Green.Fprintf(out, "Found cached artifact for %s\n", a.ImageName)
+		built = append(built, build.Artifact{
+			ImageName: a.ImageName,
+			Tag:       tag,
+		})
+	}
+
+	return needToBuild, built, nil
+}
+
+// retrieveCachedArtifactDetails decides what, if anything, needs to happen
+// for a single artifact so that a previously built image can be reused
+// instead of rebuilding it.
+func (c *Cache) retrieveCachedArtifactDetails(ctx context.Context, a *latest.Artifact) (*cachedArtifactDetails, error) {
+	hash, err := c.hasher.Hash(ctx, a)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting hash for artifact %s", a.ImageName)
+	}
+	a.WorkspaceHash = hash
+
+	entry, ok := c.artifactCache[hash]
+	if !ok {
+		return &cachedArtifactDetails{needsRebuild: true}, nil
+	}
+	hashTag := fmt.Sprintf("%s:%s", a.ImageName, hash)
+
+	if c.client == nil {
+		if imgExistsRemotely(hashTag, entry.Digest) {
+			return &cachedArtifactDetails{hashTag: hashTag}, nil
+		}
+		return &cachedArtifactDetails{needsRebuild: true}, nil
+	}
+
+	if id, err := c.client.ImageID(ctx, hashTag); err == nil && id != "" {
+		return &cachedArtifactDetails{hashTag: hashTag, prebuiltImage: hashTag}, nil
+	}
+
+	prebuiltImages, err := c.retrievePrebuiltImage(a.ImageName, entry)
+	if err != nil {
+		return &cachedArtifactDetails{needsRebuild: true}, nil
+	}
+	prebuiltImage := prebuiltImages[0]
+
+	needsPush := c.pushImages && !c.localCluster
+	if needsPush && imgExistsRemotely(hashTag, entry.Digest) {
+		needsPush = false
+	}
+
+	return &cachedArtifactDetails{
+		hashTag:       hashTag,
+		prebuiltImage: prebuiltImage,
+		needsRetag:    prebuiltImage != hashTag,
+		needsPush:     needsPush,
+	}, nil
+}
+
+// danglingTag is how the Docker daemon represents an image with no
+// repository tag of its own.
+const danglingTag = "<none>:<none>"
+
+// retrievePrebuiltImage searches the local image list for images matching
+// details and returns the tags they're known under. A pinned
+// repository@digest reference is tried first, then the content-addressable
+// store; a match through either returns a single tag, the matched image's
+// first usable RepoTag, without preferring one named after
+// artifactImageName. Otherwise every image whose RepoDigests contain
+// details.Digest or, when details has no digest, whose ID is details.ID
+// contributes all of its candidate tags, so the caller can pick one
+// deterministically.
+//
+// Those candidates are ordered so that a tag already named after
+// artifactImageName comes first, making a retag a no-op. The dangling
+// `<none>:<none>` tag is only ever returned when it's the only tag an exact
+// digest match has to offer.
+func (c *Cache) retrievePrebuiltImage(artifactImageName string, details ImageDetails) ([]string, error) {
+	if details.Ref != "" {
+		if ref, err := ParseArtifactRef(details.Ref); err == nil {
+			if tag, ok := c.findImageByRef(ref); ok {
+				return []string{tag}, nil
+			}
+		}
+	}
+
+	if c.store != nil {
+		if tag, ok := c.findImageInStore(details); ok {
+			return []string{tag}, nil
+		}
+	}
+
+	var candidates []string
+	for _, image := range c.imageList {
+		if !matchesDetails(image, details) {
+			continue
+		}
+		candidates = append(candidates, candidateTags(image, details.Digest != "")...)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no image found matching cache entry %+v", details)
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return tagPriority(candidates[i], artifactImageName) < tagPriority(candidates[j], artifactImageName)
+	})
+	return candidates, nil
+}
+
+// matchesDetails reports whether image is the one details refers to: a
+// digest match if details carries a digest, otherwise an image ID match.
+func matchesDetails(image types.ImageSummary, details ImageDetails) bool {
+	if details.Digest != "" {
+		for _, repoDigest := range image.RepoDigests {
+			if digestSuffix(repoDigest) == details.Digest {
+				return true
+			}
+		}
+		return false
+	}
+	return details.ID != "" && image.ID == details.ID
+}
+
+// candidateTags returns the usable tags of image: every RepoTag except the
+// dangling placeholder, which is only kept when it's the only tag image
+// has and the match was an exact digest match. A dangling tag matched only
+// by image ID isn't trustworthy enough to retag from, since IDs can be
+// reused once an image is removed; an exact digest match is.
+func candidateTags(image types.ImageSummary, exactDigestMatch bool) []string {
+	var tags []string
+	for _, tag := range image.RepoTags {
+		if tag != danglingTag {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 && exactDigestMatch {
+		return image.RepoTags
+	}
+	return tags
+}
+
+// tagPriority orders a tag whose repository matches imageName ahead of
+// every other tag.
+func tagPriority(tag, imageName string) int {
+	if repository(tag) == imageName {
+		return 0
+	}
+	return 1
+}
+
+// repository returns the repository portion of a `repository:tag` string.
+func repository(tag string) string {
+	idx := strings.LastIndex(tag, ":")
+	if idx == -1 {
+		return tag
+	}
+	return tag[:idx]
+}
+
+// findImageByRef looks for an image whose RepoDigests contains the exact
+// `repository@digest` form of ref, returning one of its repository tags.
+// RepoDigests are normalized before comparing, since the daemon reports
+// them in familiar form (`image@sha256:...`) while ref is fully qualified.
+func (c *Cache) findImageByRef(ref ArtifactRef) (string, bool) {
+	full := ref.String()
+	for _, image := range c.imageList {
+		if len(image.RepoTags) == 0 {
+			continue
+		}
+		for _, repoDigest := range image.RepoDigests {
+			if named, err := reference.ParseNormalizedNamed(repoDigest); err == nil && named.String() == full {
+				return image.RepoTags[0], true
+			}
+		}
+	}
+	return "", false
+}
+
+// findImageInStore looks the cache entry up in the content-addressable
+// store, by image ID if known, otherwise by digest, and returns the first
+// non-dangling tag of the local image with the resolved ID.
+func (c *Cache) findImageInStore(details ImageDetails) (string, bool) {
+	id, ok := c.resolveStoreID(details)
+	if !ok {
+		return "", false
+	}
+
+	for _, image := range c.imageList {
+		if image.ID != id {
+			continue
+		}
+		if tags := candidateTags(image, false); len(tags) > 0 {
+			return tags[0], true
+		}
+	}
+	return "", false
+}
+
+// resolveStoreID resolves details to an image ID recorded in the store. An
+// ID is treated as a prefix, so that a short ID such as the one accepted by
+// `skaffold build --cache-lookup` resolves the same way as a full one. A
+// digest is resolved exactly via Resolve instead, since CacheArtifacts and
+// MigrateArtifactCache record a digest as a ref (ref -> ID), and the ID it
+// maps to is the image's own ID whenever one is known.
+func (c *Cache) resolveStoreID(details ImageDetails) (string, bool) {
+	if details.ID != "" {
+		ids := c.store.Search(details.ID)
+		if len(ids) != 1 {
+			return "", false
+		}
+		return ids[0], true
+	}
+	if details.Digest != "" {
+		img, ok := c.store.Resolve(details.Digest)
+		if !ok {
+			return "", false
+		}
+		return img.ID, true
+	}
+	return "", false
+}
+
+// digestSuffix returns the digest portion of a `repository@sha256:...`
+// RepoDigest entry, or the whole string if it isn't in that form.
+func digestSuffix(repoDigest string) string {
+	idx := strings.LastIndex(repoDigest, "@")
+	if idx == -1 {
+		return repoDigest
+	}
+	return repoDigest[idx+1:]
+}
@@ -25,6 +25,7 @@ import (
 	"testing"
 
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build"
+	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/build/cache/imagestore"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/docker"
 	"github.com/GoogleContainerTools/skaffold/pkg/skaffold/schema/latest"
 	"github.com/GoogleContainerTools/skaffold/testutil"
@@ -120,16 +121,57 @@ func Test_RetrieveCachedArtifacts(t *testing.T) {
 			expectedArtifacts:    []*latest.Artifact{{ImageName: "image2", WorkspaceHash: "hash2"}},
 			expectedBuildResults: []build.Artifact{{ImageName: "image1", Tag: "image1:hash"}},
 		},
+		{
+			// Unlike the other cases above, TagToImageID has no entry for
+			// "image1:hash", so the exact-tag ImageID shortcut in
+			// retrieveCachedArtifactDetails can't succeed: this only passes if RetrieveCachedArtifacts
+			// itself populates cache.imageList from the daemon before
+			// retrievePrebuiltImage scans it.
+			name: "image found by digest through image list populated by RetrieveCachedArtifacts",
+			cache: &Cache{
+				useCache:      true,
+				artifactCache: ArtifactCache{"hash": ImageDetails{Digest: digest}},
+			},
+			hashes: map[string]string{"image1": "hash"},
+			api: testutil.FakeAPIClient{
+				ImageSummaries: []types.ImageSummary{
+					{
+						RepoDigests: []string{image},
+						RepoTags:    []string{"image1:oldtag"},
+					},
+				},
+			},
+			artifacts:            []*latest.Artifact{{ImageName: "image1"}},
+			expectedBuildResults: []build.Artifact{{ImageName: "image1", Tag: "image1:hash"}},
+		},
+		{
+			// Exercises the findImageByRef pinning path end-to-end: the
+			// cache entry carries a canonical Ref instead of a bare Digest,
+			// and, as above, there's no TagToImageID entry for
+			// "image1:hash", so only a successful findImageByRef lookup
+			// against the daemon-populated imageList can satisfy this.
+			name: "image found via canonical Ref through RetrieveCachedArtifacts",
+			cache: &Cache{
+				useCache:      true,
+				artifactCache: ArtifactCache{"hash": ImageDetails{Ref: image}},
+			},
+			hashes: map[string]string{"image1": "hash"},
+			api: testutil.FakeAPIClient{
+				ImageSummaries: []types.ImageSummary{
+					{
+						RepoDigests: []string{image},
+						RepoTags:    []string{"image1:oldtag"},
+					},
+				},
+			},
+			artifacts:            []*latest.Artifact{{ImageName: "image1"}},
+			expectedBuildResults: []build.Artifact{{ImageName: "image1", Tag: "image1:hash"}},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			originalHash := hashForArtifact
-			hashForArtifact = mockHashForArtifact(test.hashes)
-			defer func() {
-				hashForArtifact = originalHash
-			}()
-
+			test.cache.hasher = mockHasher(test.hashes)
 			test.cache.client = docker.NewLocalDaemon(&test.api, nil, false)
 
 			actualArtifacts, actualBuildResults, err := test.cache.RetrieveCachedArtifacts(context.Background(), os.Stdout, test.artifacts)
@@ -307,11 +349,7 @@ func TestRetrieveCachedArtifactDetails(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			originalHash := hashForArtifact
-			hashForArtifact = mockHashForArtifact(test.hashes)
-			defer func() {
-				hashForArtifact = originalHash
-			}()
+			test.cache.hasher = mockHasher(test.hashes)
 
 			originalRemoteDigest := remoteDigest
 			remoteDigest = func(string) (string, error) {
@@ -344,13 +382,26 @@ func TestRetrieveCachedArtifactDetails(t *testing.T) {
 	}
 }
 
+// storeWithDigest returns an imagestore.Store with a single AddDigest(digest,
+// id, digest) entry, the way CacheArtifacts and MigrateArtifactCache record
+// the digest of a cache entry whose image ID is known.
+func storeWithDigest(t *testing.T, digest, id string) imagestore.Store {
+	t.Helper()
+	store := imagestore.NewMemoryStore()
+	if err := store.AddDigest(digest, id, digest); err != nil {
+		t.Fatalf("AddDigest: %v", err)
+	}
+	return store
+}
+
 func TestRetrievePrebuiltImage(t *testing.T) {
 	tests := []struct {
 		name         string
 		cache        *Cache
+		imageName    string
 		imageDetails ImageDetails
 		shouldErr    bool
-		expected     string
+		expected     []string
 	}{
 		{
 			name: "one image id exists",
@@ -366,10 +417,11 @@ func TestRetrievePrebuiltImage(t *testing.T) {
 					},
 				},
 			},
+			imageName: "image",
 			imageDetails: ImageDetails{
 				Digest: digest,
 			},
-			expected: "image:mytag",
+			expected: []string{"image:mytag"},
 		},
 		{
 			name: "no image id exists",
@@ -386,10 +438,11 @@ func TestRetrievePrebuiltImage(t *testing.T) {
 				},
 			},
 			shouldErr: true,
+			imageName: "image",
 			imageDetails: ImageDetails{
 				Digest: "dne",
 			},
-			expected: "",
+			expected: nil,
 		},
 		{
 			name: "one image id exists",
@@ -405,10 +458,11 @@ func TestRetrievePrebuiltImage(t *testing.T) {
 					},
 				},
 			},
+			imageName: "image3",
 			imageDetails: ImageDetails{
 				ID: "imageid",
 			},
-			expected: "image3",
+			expected: []string{"image3"},
 		},
 		{
 			name: "multiple image ids exist",
@@ -424,10 +478,11 @@ func TestRetrievePrebuiltImage(t *testing.T) {
 					},
 				},
 			},
+			imageName: "image4",
 			imageDetails: ImageDetails{
 				ID: "imageid",
 			},
-			expected: "image3",
+			expected: []string{"image4", "image3"},
 		},
 		{
 			name: "no image id exists",
@@ -443,17 +498,116 @@ func TestRetrievePrebuiltImage(t *testing.T) {
 					},
 				},
 			},
+			imageName: "image",
+			imageDetails: ImageDetails{
+				ID: "imageid",
+			},
+			shouldErr: true,
+			expected:  nil,
+		},
+		{
+			name: "dangling image is returned on an exact digest match",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags:    []string{"<none>:<none>"},
+						RepoDigests: []string{image},
+					},
+				},
+			},
+			imageName: "image",
+			imageDetails: ImageDetails{
+				Digest: digest,
+			},
+			expected: []string{"<none>:<none>"},
+		},
+		{
+			name: "dangling image is skipped when matched only by id",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags: []string{"<none>:<none>"},
+						ID:       "imageid",
+					},
+				},
+			},
+			imageName: "image",
 			imageDetails: ImageDetails{
 				ID: "imageid",
 			},
 			shouldErr: true,
-			expected:  "",
+			expected:  nil,
+		},
+		{
+			name: "tag matching the artifact name is preferred",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags: []string{"other/image:hash", "myimage:hash"},
+						ID:       "id1",
+					},
+				},
+			},
+			imageName: "myimage",
+			imageDetails: ImageDetails{
+				ID: "id1",
+			},
+			expected: []string{"myimage:hash", "other/image:hash"},
+		},
+		{
+			name: "image tagged under multiple repositories",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags:    []string{"repoA:tag1", "repoB:tag2"},
+						RepoDigests: []string{image},
+					},
+				},
+			},
+			imageName: "repoB",
+			imageDetails: ImageDetails{
+				Digest: digest,
+			},
+			expected: []string{"repoB:tag2", "repoA:tag1"},
+		},
+		{
+			name: "image found via canonical Ref through findImageByRef",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags:    []string{"image5:tag"},
+						RepoDigests: []string{"image5@" + digest},
+					},
+				},
+			},
+			imageName: "image5",
+			imageDetails: ImageDetails{
+				Ref: "image5@" + digest,
+			},
+			expected: []string{"image5:tag"},
+		},
+		{
+			name: "image found via content-addressable store by digest ref",
+			cache: &Cache{
+				imageList: []types.ImageSummary{
+					{
+						RepoTags: []string{"image6:tag"},
+						ID:       "imageid3",
+					},
+				},
+				store: storeWithDigest(t, "sha256:storeonly", "imageid3"),
+			},
+			imageName: "image6",
+			imageDetails: ImageDetails{
+				Digest: "sha256:storeonly",
+			},
+			expected: []string{"image6:tag"},
 		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			test.cache.client = docker.NewLocalDaemon(&testutil.FakeAPIClient{}, nil, false)
-			actual, err := test.cache.retrievePrebuiltImage(test.imageDetails)
+			actual, err := test.cache.retrievePrebuiltImage(test.imageName, test.imageDetails)
 			testutil.CheckErrorAndDeepEqual(t, test.shouldErr, err, test.expected, actual)
 		})
 	}
@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Skaffold Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// SkaffoldOptions are the flags and options that control how skaffold
+// builds, deploys, and watches a project.
+type SkaffoldOptions struct {
+	// KubeContext is the kubeconfig context skaffold talks to the cluster
+	// through. It's also what the build cache compares against to decide
+	// whether a cluster is "local", and so doesn't need a pushed image.
+	KubeContext string
+
+	// CacheArtifacts enables reusing previously built artifacts instead of
+	// rebuilding them when their build inputs haven't changed.
+	CacheArtifacts bool
+
+	// CacheFile is the path to the on-disk artifact cache. An empty value
+	// selects the default, `~/.skaffold/cache`.
+	CacheFile string
+
+	// CacheHashAlgorithm selects the strategy the build cache uses to hash
+	// an artifact's build inputs. The empty value selects the default,
+	// content-hash based strategy; "layer-digest" selects a strategy based
+	// on resolved base image layers instead.
+	CacheHashAlgorithm string
+
+	// CacheRepo, when set, makes the artifact cache registry-backed instead
+	// of local: cache entries are stored as OCI artifacts in this
+	// repository instead of the local daemon/filesystem, so CI runners and
+	// other developers can share build results without a local Docker
+	// daemon.
+	CacheRepo string
+}